@@ -0,0 +1,203 @@
+// Package deliverability checks whether a domain is likely to be able to
+// receive mail at all, using MX records and (optionally) the policy
+// published in its SPF record. It is meant to be consulted once per
+// domain before an email is counted, so results are cached behind a
+// bounded, TTL'd LRU.
+package deliverability
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of an SPF policy check for a domain. It mirrors
+// the result set defined by RFC 7208.
+type Result string
+
+const (
+	Pass      Result = "pass"
+	Fail      Result = "fail"
+	SoftFail  Result = "softfail"
+	Neutral   Result = "neutral"
+	None      Result = "none"
+	PermError Result = "permerror"
+	TempError Result = "temperror"
+)
+
+// Resolver is the subset of *net.Resolver this package depends on. Tests
+// use MockResolver so they don't touch the network.
+type Resolver interface {
+	LookupMX(ctx context.Context, domain string) ([]*net.MX, error)
+	LookupTXT(ctx context.Context, domain string) ([]string, error)
+}
+
+// Deliverability is the cached result of checking a single domain
+type Deliverability struct {
+	// HasMX is true if the domain has at least one MX record
+	HasMX bool
+	// SPF is the domain's SPF policy result. It is the zero value when
+	// CheckerOptions.CheckSPF was false. SPF authorizes senders, not
+	// recipients, and this package has no connecting client IP to test
+	// the ip4/ip6/a/mx mechanisms against, so SPF reflects only which
+	// qualifier the record falls through to, not whether the domain can
+	// actually receive mail; prefer HasMX for that.
+	SPF Result
+}
+
+// CheckerOptions configures a Checker
+type CheckerOptions struct {
+	// Resolver performs the DNS lookups. Defaults to net.DefaultResolver.
+	Resolver Resolver
+	// CheckSPF additionally resolves and parses the domain's SPF record.
+	// Defaults to false (MX presence only).
+	CheckSPF bool
+	// CacheSize bounds how many domains are held in the LRU cache.
+	// Defaults to 10,000.
+	CacheSize int
+	// CacheTTL is how long a cached result is considered valid.
+	// Defaults to 1 hour.
+	CacheTTL time.Duration
+}
+
+// Checker looks up and caches the deliverability of domains
+type Checker struct {
+	resolver Resolver
+	checkSPF bool
+	cache    *lruCache
+}
+
+// NewChecker creates a Checker configured with opts
+func NewChecker(opts CheckerOptions) *Checker {
+	resolver := opts.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	cacheSize := opts.CacheSize
+	if cacheSize <= 0 {
+		cacheSize = 10_000
+	}
+
+	cacheTTL := opts.CacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = time.Hour
+	}
+
+	return &Checker{
+		resolver: resolver,
+		checkSPF: opts.CheckSPF,
+		cache:    newLRUCache(cacheSize, cacheTTL),
+	}
+}
+
+// Check returns the Deliverability of domain, consulting the cache first
+// and falling back to DNS lookups through the configured Resolver
+func (c *Checker) Check(ctx context.Context, domain string) (Deliverability, error) {
+	if cached, ok := c.cache.get(domain); ok {
+		return cached, nil
+	}
+
+	mxRecords, err := c.resolver.LookupMX(ctx, domain)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			err = nil
+		} else {
+			return Deliverability{}, err
+		}
+	}
+
+	result := Deliverability{
+		HasMX: len(mxRecords) > 0,
+	}
+
+	if c.checkSPF {
+		lookups := 0
+		spf, spfErr := c.evaluateSPF(ctx, domain, &lookups)
+		if spfErr != nil && spf == "" {
+			spf = TempError
+		}
+		result.SPF = spf
+	}
+
+	c.cache.set(domain, result)
+
+	return result, nil
+}
+
+// cacheEntry is the value stored in the LRU's backing list
+type cacheEntry struct {
+	domain    string
+	value     Deliverability
+	expiresAt time.Time
+}
+
+// lruCache is a small, bounded, TTL'd LRU cache keyed by domain. It exists
+// so that processing a million rows for the same domain hits DNS once.
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+func newLRUCache(capacity int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *lruCache) get(domain string) (Deliverability, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[domain]
+	if !ok {
+		return Deliverability{}, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.items, domain)
+		return Deliverability{}, false
+	}
+
+	c.order.MoveToFront(element)
+
+	return entry.value, true
+}
+
+func (c *lruCache) set(domain string, value Deliverability) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[domain]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.value = value
+		entry.expiresAt = time.Now().Add(c.ttl)
+		c.order.MoveToFront(element)
+		return
+	}
+
+	element := c.order.PushFront(&cacheEntry{
+		domain:    domain,
+		value:     value,
+		expiresAt: time.Now().Add(c.ttl),
+	})
+	c.items[domain] = element
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(*cacheEntry).domain)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package deliverability
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCheckHasMX(t *testing.T) {
+	resolver := NewMockResolver()
+	resolver.MX["example.com"] = []*net.MX{{Host: "mx.example.com."}}
+
+	checker := NewChecker(CheckerOptions{Resolver: resolver})
+
+	result, err := checker.Check(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.HasMX {
+		t.Errorf("expected HasMX to be true")
+	}
+}
+
+func TestCheckNoMX(t *testing.T) {
+	resolver := NewMockResolver()
+	checker := NewChecker(CheckerOptions{Resolver: resolver})
+
+	result, err := checker.Check(context.Background(), "no-mx.example")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.HasMX {
+		t.Errorf("expected HasMX to be false")
+	}
+}
+
+func TestCheckCachesResult(t *testing.T) {
+	calls := 0
+	resolver := NewMockResolver()
+	resolver.MX["example.com"] = []*net.MX{{Host: "mx.example.com."}}
+	countingResolver := &countingMXResolver{MockResolver: resolver, calls: &calls}
+
+	checker := NewChecker(CheckerOptions{Resolver: countingResolver, CacheTTL: time.Minute})
+
+	for i := 0; i < 5; i++ {
+		if _, err := checker.Check(context.Background(), "example.com"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected a single DNS lookup due to caching, got %d", calls)
+	}
+}
+
+type countingMXResolver struct {
+	*MockResolver
+	calls *int
+}
+
+func (r *countingMXResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	*r.calls++
+	return r.MockResolver.LookupMX(ctx, domain)
+}
+
+func TestEvaluateSPFFail(t *testing.T) {
+	resolver := NewMockResolver()
+	resolver.TXT["example.com"] = []string{"v=spf1 ip4:10.0.0.0/8 -all"}
+
+	checker := NewChecker(CheckerOptions{Resolver: resolver, CheckSPF: true})
+	result, err := checker.Check(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.SPF != Fail {
+		t.Errorf("expected %s, got %s", Fail, result.SPF)
+	}
+}
+
+func TestEvaluateSPFNone(t *testing.T) {
+	resolver := NewMockResolver()
+
+	checker := NewChecker(CheckerOptions{Resolver: resolver, CheckSPF: true})
+	result, err := checker.Check(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.SPF != None {
+		t.Errorf("expected %s, got %s", None, result.SPF)
+	}
+}
+
+func TestEvaluateSPFInclude(t *testing.T) {
+	resolver := NewMockResolver()
+	resolver.TXT["example.com"] = []string{"v=spf1 include:_spf.example.net ~all"}
+	resolver.TXT["_spf.example.net"] = []string{"v=spf1 ip4:10.0.0.0/8 -all"}
+
+	checker := NewChecker(CheckerOptions{Resolver: resolver, CheckSPF: true})
+	result, err := checker.Check(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The included record resolves to Fail, which is a non-match for the
+	// include mechanism (RFC 7208 section 5.2), so evaluation continues to
+	// the outer record's ~all.
+	if result.SPF != SoftFail {
+		t.Errorf("expected a non-matching include to fall through to %s, got %s", SoftFail, result.SPF)
+	}
+}
+
+func TestEvaluateSPFIncludePassUsesOuterQualifier(t *testing.T) {
+	resolver := NewMockResolver()
+	resolver.TXT["example.com"] = []string{"v=spf1 -include:_spf.example.net -all"}
+	resolver.TXT["_spf.example.net"] = []string{"v=spf1 +all"}
+
+	checker := NewChecker(CheckerOptions{Resolver: resolver, CheckSPF: true})
+	result, err := checker.Check(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The included record resolves to Pass, so the match is settled using
+	// the include mechanism's own "-" qualifier, not the inner record's "+".
+	if result.SPF != Fail {
+		t.Errorf("expected the include mechanism's own qualifier to settle the result as %s, got %s", Fail, result.SPF)
+	}
+}
+
+func TestEvaluateSPFIncludePermErrorPropagates(t *testing.T) {
+	resolver := NewMockResolver()
+	resolver.TXT["example.com"] = []string{"v=spf1 include:_spf.example.net -all"}
+	// _spf.example.net publishes two SPF records, which is itself a
+	// PermError (RFC 7208 section 4.5).
+	resolver.TXT["_spf.example.net"] = []string{"v=spf1 -all", "v=spf1 ~all"}
+
+	checker := NewChecker(CheckerOptions{Resolver: resolver, CheckSPF: true})
+	result, err := checker.Check(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.SPF != PermError {
+		t.Errorf("expected an inner PermError to propagate as %s, got %s", PermError, result.SPF)
+	}
+}
+
+func TestEvaluateSPFIncludeNoRecordIsPermError(t *testing.T) {
+	resolver := NewMockResolver()
+	resolver.TXT["example.com"] = []string{"v=spf1 include:_spf.example.net -all"}
+	// _spf.example.net has no SPF record at all.
+
+	checker := NewChecker(CheckerOptions{Resolver: resolver, CheckSPF: true})
+	result, err := checker.Check(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.SPF != PermError {
+		t.Errorf("expected an inner None to propagate as %s, got %s", PermError, result.SPF)
+	}
+}
+
+func TestEvaluateSPFPermErrorOnMultipleRecords(t *testing.T) {
+	resolver := NewMockResolver()
+	resolver.TXT["example.com"] = []string{"v=spf1 -all", "v=spf1 ~all"}
+
+	checker := NewChecker(CheckerOptions{Resolver: resolver, CheckSPF: true})
+	result, err := checker.Check(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.SPF != PermError {
+		t.Errorf("expected %s, got %s", PermError, result.SPF)
+	}
+}
+
+func TestCheckPropagatesMXLookupError(t *testing.T) {
+	resolver := NewMockResolver()
+	resolver.MXErr["example.com"] = errors.New("boom")
+
+	checker := NewChecker(CheckerOptions{Resolver: resolver})
+	if _, err := checker.Check(context.Background(), "example.com"); err == nil {
+		t.Errorf("expected an error from the MX lookup to propagate")
+	}
+}
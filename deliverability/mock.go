@@ -0,0 +1,39 @@
+package deliverability
+
+import (
+	"context"
+	"net"
+)
+
+// MockResolver is a Resolver whose answers are configured in-memory, so
+// tests can exercise Checker without touching the network.
+type MockResolver struct {
+	MX     map[string][]*net.MX
+	TXT    map[string][]string
+	MXErr  map[string]error
+	TXTErr map[string]error
+}
+
+// NewMockResolver returns an empty MockResolver ready to be configured
+func NewMockResolver() *MockResolver {
+	return &MockResolver{
+		MX:     make(map[string][]*net.MX),
+		TXT:    make(map[string][]string),
+		MXErr:  make(map[string]error),
+		TXTErr: make(map[string]error),
+	}
+}
+
+func (m *MockResolver) LookupMX(ctx context.Context, domain string) ([]*net.MX, error) {
+	if err, ok := m.MXErr[domain]; ok {
+		return nil, err
+	}
+	return m.MX[domain], nil
+}
+
+func (m *MockResolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	if err, ok := m.TXTErr[domain]; ok {
+		return nil, err
+	}
+	return m.TXT[domain], nil
+}
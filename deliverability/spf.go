@@ -0,0 +1,177 @@
+package deliverability
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// maxSPFLookups bounds the number of DNS-querying mechanisms (include, a,
+// mx, ...) evaluated per check, matching the limit RFC 7208 section 4.6.4
+// places on SPF evaluation to avoid DoS via maliciously nested records.
+const maxSPFLookups = 10
+
+// qualifier is the single-character prefix on an SPF mechanism that
+// determines the Result it produces, eg. "-all" is a Fail qualifier.
+type qualifier byte
+
+const (
+	qualPass     qualifier = '+'
+	qualFail     qualifier = '-'
+	qualSoftFail qualifier = '~'
+	qualNeutral  qualifier = '?'
+)
+
+func (q qualifier) result() Result {
+	switch q {
+	case qualFail:
+		return Fail
+	case qualSoftFail:
+		return SoftFail
+	case qualNeutral:
+		return Neutral
+	default:
+		return Pass
+	}
+}
+
+// evaluateSPF resolves domain's SPF record (if any) and walks its
+// mechanisms to determine the domain's overall policy, recursing into
+// "include" mechanisms as needed. lookups is shared across the whole
+// recursive evaluation so the maxSPFLookups bound applies to the entire
+// chain, not just one domain's record.
+func (c *Checker) evaluateSPF(ctx context.Context, domain string, lookups *int) (Result, error) {
+	txtRecords, err := c.resolver.LookupTXT(ctx, domain)
+	if err != nil {
+		return TempError, err
+	}
+
+	record, err := findSPFRecord(txtRecords)
+	if err != nil {
+		return PermError, err
+	}
+	if record == "" {
+		return None, nil
+	}
+
+	terms := strings.Fields(record)
+	for _, term := range terms[1:] { // terms[0] is the "v=spf1" version tag
+		qual, mechanism, value := parseTerm(term)
+
+		switch mechanism {
+		case "all":
+			return qual.result(), nil
+
+		case "include":
+			*lookups++
+			if *lookups > maxSPFLookups {
+				return PermError, fmt.Errorf("too many DNS lookups while evaluating SPF for %s", domain)
+			}
+			if value == "" {
+				return PermError, fmt.Errorf("include mechanism missing a domain in %q", term)
+			}
+			result, resultErr := c.evaluateSPF(ctx, value, lookups)
+			// RFC 7208 section 5.2: include only matches on an inner Pass,
+			// in which case the result is the include mechanism's own
+			// qualifier (not the inner record's). An inner None or
+			// PermError makes the outer record a PermError, an inner
+			// TempError propagates as TempError, and Fail/SoftFail/Neutral
+			// are a non-match that falls through to later terms. Check
+			// result itself before the generic resultErr case below:
+			// PermError is always accompanied by a non-nil error, so a
+			// plain "if resultErr != nil" would mislabel it TempError, and
+			// None comes with a nil error, so that check alone would let
+			// it fall through as a non-match.
+			switch result {
+			case Pass:
+				return qual.result(), nil
+			case None:
+				return PermError, fmt.Errorf("include %q has no SPF record", value)
+			case PermError:
+				return PermError, fmt.Errorf("include %q resolved to %s: %w", value, result, resultErr)
+			case TempError:
+				return TempError, fmt.Errorf("include %q resolved to %s: %w", value, result, resultErr)
+			}
+			if resultErr != nil {
+				return TempError, resultErr
+			}
+
+		case "a", "mx":
+			*lookups++
+			if *lookups > maxSPFLookups {
+				return PermError, fmt.Errorf("too many DNS lookups while evaluating SPF for %s", domain)
+			}
+			// Without a connecting client IP we can't test membership in
+			// the mechanism's address set, so its presence is accepted
+			// as valid syntax and evaluation continues to the next term.
+
+		case "ip4", "ip6":
+			if !validIPMechanism(value) {
+				return PermError, fmt.Errorf("invalid %s mechanism value %q", mechanism, term)
+			}
+
+		default:
+			// Unknown mechanisms/modifiers (eg. "redirect=", "exp=") are
+			// ignored per RFC 7208 section 6, rather than failing the
+			// whole record.
+		}
+	}
+
+	// RFC 7208 section 4.7: if the record has no "all" mechanism and
+	// nothing else matched, the result is Neutral.
+	return Neutral, nil
+}
+
+// findSPFRecord returns the single "v=spf1" record among txtRecords, or an
+// error if more than one is present (ambiguous policy is a PermError per
+// RFC 7208 section 4.5)
+func findSPFRecord(txtRecords []string) (string, error) {
+	var spfRecord string
+	count := 0
+	for _, record := range txtRecords {
+		if strings.HasPrefix(strings.ToLower(record), "v=spf1") {
+			spfRecord = record
+			count++
+		}
+	}
+
+	if count > 1 {
+		return "", fmt.Errorf("multiple SPF records found")
+	}
+
+	return spfRecord, nil
+}
+
+// parseTerm splits an SPF term into its qualifier, mechanism name and
+// value, eg. "-include:_spf.example.com" -> (qualFail, "include",
+// "_spf.example.com")
+func parseTerm(term string) (qualifier, string, string) {
+	qual := qualPass
+	if len(term) > 0 {
+		switch term[0] {
+		case '+', '-', '~', '?':
+			qual = qualifier(term[0])
+			term = term[1:]
+		}
+	}
+
+	mechanism, value := term, ""
+	if idx := strings.IndexAny(term, ":="); idx >= 0 {
+		mechanism, value = term[:idx], term[idx+1:]
+	}
+
+	return qual, strings.ToLower(mechanism), value
+}
+
+// validIPMechanism reports whether value is a valid IPv4/IPv6 address or
+// CIDR range, as required by the ip4/ip6 mechanisms
+func validIPMechanism(value string) bool {
+	if value == "" {
+		return false
+	}
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return true
+	}
+	return net.ParseIP(value) != nil
+}
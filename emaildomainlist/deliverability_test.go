@@ -0,0 +1,145 @@
+package emaildomainlist
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/PanagiotisPtr/csv-email-counter/deliverability"
+)
+
+func TestAddEmailAddressCtxDropsUndeliverableDomains(t *testing.T) {
+	resolver := deliverability.NewMockResolver()
+	// spam.example has no MX record, which is what makes it undeliverable;
+	// its SPF "-all" is irrelevant to the drop decision (see
+	// TestAddEmailAddressCtxKeepsDomainsWithMXDespiteSPFFail).
+	resolver.TXT["spam.example"] = []string{"v=spf1 -all"}
+	resolver.MX["good.example"] = []*net.MX{{Host: "mx.good.example."}}
+	resolver.TXT["good.example"] = []string{"v=spf1 ip4:10.0.0.0/8 +all"}
+
+	edl := NewEmailDomainsListWithOptions(Options{
+		DeliverabilityChecker: deliverability.NewChecker(deliverability.CheckerOptions{
+			Resolver: resolver,
+			CheckSPF: true,
+		}),
+		DeliverabilityPolicy: DeliverabilityPolicyDrop,
+	})
+
+	assertNoError(edl.AddEmailAddressCtx(context.Background(), "a@spam.example"), t)
+	assertNoError(edl.AddEmailAddressCtx(context.Background(), "b@good.example"), t)
+
+	result := edl.GetDomainCounts()
+	if len(result) != 1 {
+		t.Fatalf("expected the domain with no MX records to be dropped, got %d domains", len(result))
+	}
+	if result[0].Domain != "good.example" {
+		t.Errorf("expected only good.example to remain, got %s", result[0].Domain)
+	}
+}
+
+func TestAddEmailAddressCtxKeepsDomainsWithMXDespiteSPFFail(t *testing.T) {
+	resolver := deliverability.NewMockResolver()
+	// A common, legitimate record: authorize only the domain's own MX
+	// hosts to send, and fail everything else. With no connecting client
+	// IP to test "mx" against, evaluateSPF falls through the "-all"
+	// catch-all and resolves this to Fail even though the domain receives
+	// mail fine - that must not drive the policy.
+	resolver.MX["example.com"] = []*net.MX{{Host: "mx.example.com."}}
+	resolver.TXT["example.com"] = []string{"v=spf1 mx -all"}
+
+	edl := NewEmailDomainsListWithOptions(Options{
+		DeliverabilityChecker: deliverability.NewChecker(deliverability.CheckerOptions{
+			Resolver: resolver,
+			CheckSPF: true,
+		}),
+		DeliverabilityPolicy: DeliverabilityPolicyDrop,
+	})
+
+	assertNoError(edl.AddEmailAddressCtx(context.Background(), "a@example.com"), t)
+
+	result := edl.GetDomainCounts()
+	if len(result) != 1 || result[0].NumberOfEmailAddresses != 1 {
+		t.Fatalf("expected example.com to still be counted despite its SPF Fail, got %+v", result)
+	}
+	if result[0].Deliverability.SPF != deliverability.Fail {
+		t.Errorf("expected Deliverability.SPF to still report %s for visibility, got %s", deliverability.Fail, result[0].Deliverability.SPF)
+	}
+}
+
+func TestAddEmailAddressCtxDropsDomainsWithNoMX(t *testing.T) {
+	resolver := deliverability.NewMockResolver()
+	// no-mx.example has no MX records in the resolver, and SPF checking is
+	// left off entirely.
+	resolver.MX["good.example"] = []*net.MX{{Host: "mx.good.example."}}
+
+	edl := NewEmailDomainsListWithOptions(Options{
+		DeliverabilityChecker: deliverability.NewChecker(deliverability.CheckerOptions{
+			Resolver: resolver,
+		}),
+		DeliverabilityPolicy: DeliverabilityPolicyDrop,
+	})
+
+	assertNoError(edl.AddEmailAddressCtx(context.Background(), "a@no-mx.example"), t)
+	assertNoError(edl.AddEmailAddressCtx(context.Background(), "b@good.example"), t)
+
+	result := edl.GetDomainCounts()
+	if len(result) != 1 {
+		t.Fatalf("expected the domain with no MX records to be dropped, got %d domains", len(result))
+	}
+	if result[0].Domain != "good.example" {
+		t.Errorf("expected only good.example to remain, got %s", result[0].Domain)
+	}
+}
+
+func TestAddEmailAddressCtxKeepsFirstDeliverabilityResult(t *testing.T) {
+	resolver := deliverability.NewMockResolver()
+	resolver.MX["example.com"] = []*net.MX{{Host: "mx.example.com."}}
+	resolver.TXT["example.com"] = []string{"v=spf1 ip4:10.0.0.0/8 +all"}
+
+	checker := deliverability.NewChecker(deliverability.CheckerOptions{Resolver: resolver, CheckSPF: true})
+	edl := NewEmailDomainsListWithOptions(Options{DeliverabilityChecker: checker})
+
+	assertNoError(edl.AddEmailAddressCtx(context.Background(), "a@example.com"), t)
+
+	// The domain's published policy changes after the first email was
+	// counted; the cached Checker result won't reflect it within the TTL,
+	// but even if it did, DomainCount.Deliverability should still report
+	// the result computed for the first email, not the latest one.
+	resolver.TXT["example.com"] = []string{"v=spf1 -all"}
+	assertNoError(edl.AddEmailAddressCtx(context.Background(), "b@example.com"), t)
+
+	result := edl.GetDomainCounts()
+	if len(result) != 1 {
+		t.Fatalf("expected 1 domain, got %d", len(result))
+	}
+	if result[0].Deliverability.SPF != deliverability.Pass {
+		t.Errorf("expected the first email's %s result to stick, got %s", deliverability.Pass, result[0].Deliverability.SPF)
+	}
+}
+
+func TestAddEmailAddressCtxBucketsUndeliverableDomains(t *testing.T) {
+	resolver := deliverability.NewMockResolver()
+	// spam.example has no MX record, which is what makes it undeliverable.
+	resolver.TXT["spam.example"] = []string{"v=spf1 -all"}
+
+	edl := NewEmailDomainsListWithOptions(Options{
+		DeliverabilityChecker: deliverability.NewChecker(deliverability.CheckerOptions{
+			Resolver: resolver,
+			CheckSPF: true,
+		}),
+		DeliverabilityPolicy: DeliverabilityPolicyBucket,
+	})
+
+	assertNoError(edl.AddEmailAddressCtx(context.Background(), "a@spam.example"), t)
+
+	result := edl.GetDomainCounts()
+	if len(result) != 1 {
+		t.Fatalf("expected 1 domain, got %d", len(result))
+	}
+	if result[0].Domain != "spam.example (undeliverable)" {
+		t.Errorf("expected the bucketed domain name, got %s", result[0].Domain)
+	}
+	if result[0].Deliverability.SPF != deliverability.Fail {
+		t.Errorf("expected Deliverability.SPF to be %s, got %s", deliverability.Fail, result[0].Deliverability.SPF)
+	}
+}
@@ -0,0 +1,91 @@
+package emaildomainlist
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// Email is a parsed, RFC 5322-valid email address
+type Email struct {
+	// Local is the local part as parsed by net/mail, with its case kept
+	// and no LocalPartNormalizer applied (a quoted local part is unquoted)
+	Local string
+	// Domain is the domain part, lowercased per RFC 1035
+	Domain string
+	// Normalized is Local+"@"+Domain after the configured
+	// LocalPartNormalizer has been applied to Local. This is the form
+	// AddEmailAddress de-duplicates on.
+	Normalized string
+}
+
+// LocalPartNormalizer maps an email's local part to the form it should be
+// de-duplicated on. domain is provided (already lowercased) because some
+// normalizations, eg. Gmail's, only apply to specific domains.
+type LocalPartNormalizer func(local, domain string) string
+
+// DefaultLocalPartNormalizer leaves the local part unchanged
+func DefaultLocalPartNormalizer(local, domain string) string {
+	return local
+}
+
+// GmailLocalPartNormalizer implements Gmail's address equivalence rules:
+// dots in the local part are insignificant and anything from a "+" onward
+// is a tag, so "test.user+promo@gmail.com" and "testuser@gmail.com"
+// normalize to the same address. Domains other than gmail.com/
+// googlemail.com are returned unchanged.
+func GmailLocalPartNormalizer(local, domain string) string {
+	if domain != "gmail.com" && domain != "googlemail.com" {
+		return local
+	}
+
+	local = strings.ToLower(local)
+	if tagIndex := strings.Index(local, "+"); tagIndex >= 0 {
+		local = local[:tagIndex]
+	}
+
+	return strings.ReplaceAll(local, ".", "")
+}
+
+// splitEmailAddress validates email against RFC 5322 (via net/mail) and
+// splits it into its local and domain parts. Display-name forms like
+// `"Bob" <b@x.com>` are unwrapped; malformed addresses such as
+// "foo@bar@baz" are rejected.
+func splitEmailAddress(email string) (local string, domain string, err error) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", "", fmt.Errorf("not a valid RFC 5322 address: %w", err)
+	}
+
+	atIndex := strings.LastIndex(addr.Address, "@")
+	if atIndex < 0 {
+		return "", "", fmt.Errorf("address %q is missing '@'", addr.Address)
+	}
+
+	return addr.Address[:atIndex], addr.Address[atIndex+1:], nil
+}
+
+// ParseEmail validates and parses email, normalizing the local part with
+// DefaultLocalPartNormalizer (ie. no change beyond domain lowercasing).
+// Use ParseEmailWithNormalizer to de-duplicate provider-specific address
+// variants such as Gmail's "+tag" and dot forms.
+func ParseEmail(email string) (Email, error) {
+	return ParseEmailWithNormalizer(email, DefaultLocalPartNormalizer)
+}
+
+// ParseEmailWithNormalizer is ParseEmail with a pluggable LocalPartNormalizer
+func ParseEmailWithNormalizer(email string, normalize LocalPartNormalizer) (Email, error) {
+	local, domain, err := splitEmailAddress(email)
+	if err != nil {
+		return Email{}, fmt.Errorf("[ERROR][ParseEmail]: Received invalid email address %s: %w", email, err)
+	}
+
+	domain = strings.ToLower(domain)
+	normalizedLocal := normalize(local, domain)
+
+	return Email{
+		Local:      local,
+		Domain:     domain,
+		Normalized: normalizedLocal + "@" + domain,
+	}, nil
+}
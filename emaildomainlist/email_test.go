@@ -0,0 +1,86 @@
+package emaildomainlist
+
+import "testing"
+
+func TestParseEmailBaseCase(t *testing.T) {
+	email, err := ParseEmail("Test@Example.com")
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if email.Local != "Test" {
+		t.Errorf("Expected local part %s, got %s", "Test", email.Local)
+	}
+	if email.Domain != "example.com" {
+		t.Errorf("Expected domain %s, got %s", "example.com", email.Domain)
+	}
+	if email.Normalized != "Test@example.com" {
+		t.Errorf("Expected normalized address %s, got %s", "Test@example.com", email.Normalized)
+	}
+}
+
+func TestParseEmailDisplayNameForm(t *testing.T) {
+	email, err := ParseEmail(`"Bob" <b@x.com>`)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if email.Normalized != "b@x.com" {
+		t.Errorf("Expected normalized address %s, got %s", "b@x.com", email.Normalized)
+	}
+}
+
+func TestParseEmailRejectsInvalidAddress(t *testing.T) {
+	_, err := ParseEmail("foo@bar@baz")
+	if err == nil {
+		t.Errorf("Expected an error for invalid email address foo@bar@baz")
+	}
+}
+
+func TestGmailLocalPartNormalizer(t *testing.T) {
+	email, err := ParseEmailWithNormalizer("test.user+promo@gmail.com", GmailLocalPartNormalizer)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if email.Normalized != "testuser@gmail.com" {
+		t.Errorf("Expected normalized address %s, got %s", "testuser@gmail.com", email.Normalized)
+	}
+
+	other, err := ParseEmailWithNormalizer("testuser@gmail.com", GmailLocalPartNormalizer)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if other.Normalized != email.Normalized {
+		t.Errorf("Expected %s and %s to normalize to the same address", "test.user+promo@gmail.com", "testuser@gmail.com")
+	}
+}
+
+func TestGmailLocalPartNormalizerIgnoresOtherDomains(t *testing.T) {
+	email, err := ParseEmailWithNormalizer("test.user+promo@example.com", GmailLocalPartNormalizer)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+
+	if email.Normalized != "test.user+promo@example.com" {
+		t.Errorf("Expected normalized address %s, got %s", "test.user+promo@example.com", email.Normalized)
+	}
+}
+
+func TestAddEmailAddressDeduplicatesWithGmailNormalizer(t *testing.T) {
+	edl := NewEmailDomainsListWithOptions(Options{
+		LocalPartNormalizer: GmailLocalPartNormalizer,
+	})
+
+	assertNoError(edl.AddEmailAddress("test.user+promo@gmail.com"), t)
+	assertNoError(edl.AddEmailAddress("testuser@gmail.com"), t)
+
+	result := edl.GetDomainCounts()
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 domain, got %d", len(result))
+	}
+	if result[0].NumberOfEmailAddresses != 1 {
+		t.Errorf("Expected the two Gmail variants to collapse into 1 address, got %d", result[0].NumberOfEmailAddresses)
+	}
+}
@@ -2,19 +2,35 @@ package emaildomainlist
 
 import (
 	"container/list"
+	"context"
 	"encoding/csv"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"log"
 	"os"
 	"sort"
-	"strings"
+	"sync"
+
+	"github.com/PanagiotisPtr/csv-email-counter/deliverability"
 )
 
+// fnv32 hashes s deterministically, used to pick a stable sample of
+// emails under DeliverabilityPolicyDownweight
+func fnv32(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
 // Structure to store a domain with its corresponding email addresses
 type domainCounter struct {
-	domain string
-	emails map[string]bool // we need this to avoid duplicates
+	domain            string
+	emails            map[string]bool // we need this to avoid duplicates
+	hostnames         map[string]int  // child hostnames rolled up under domain, with their email counts
+	deliverability    deliverability.Deliverability
+	deliverabilitySet bool // whether deliverability holds the first-computed result, vs the zero value
+	skipped           int  // emails blocked by Options.Filter before being counted
 }
 
 // Count the number of email addresses in a domainCounter
@@ -25,24 +41,24 @@ func (dc *domainCounter) count() int {
 // Get a new domainCounter
 func newDomainCounter(domain string) *domainCounter {
 	return &domainCounter{
-		domain: domain,
-		emails: make(map[string]bool),
+		domain:    domain,
+		emails:    make(map[string]bool),
+		hostnames: make(map[string]int),
 	}
 }
 
-// Returns the domain of an email address. If an invalid email address is passed
-// an error will be returned
+// Returns the domain of an email address. The address is validated
+// against RFC 5322 (via net/mail), so display-name forms like
+// `"Bob" <b@x.com>` are unwrapped and malformed addresses such as
+// "foo@bar@baz" are rejected. If an invalid email address is passed an
+// error will be returned
 // eg. test@domain.com will return "domain.com"
 func domainOfEmailAddress(email string) (string, error) {
-	parts := strings.Split(email, "@")
-	// This validation is very basic, it should be a lot more complete
-	// Technically we should be making sure that it matches the appropriate
-	// standard (which I think might be RFC 5322)
-	isValidEmailAddress := len(parts) == 2
-	if !isValidEmailAddress {
-		return "", fmt.Errorf("[ERROR][domainOfEmailAddress]: Received invalid email address %s", email)
+	_, domain, err := splitEmailAddress(email)
+	if err != nil {
+		return "", fmt.Errorf("[ERROR][domainOfEmailAddress]: Received invalid email address %s: %w", email, err)
 	}
-	return parts[1], nil
+	return domain, nil
 }
 
 // Internal struct used for the elements of the list of the EmailDomainsList
@@ -51,18 +67,141 @@ type domainListElement struct {
 	domainCounters map[string]*domainCounter
 }
 
+// DomainNormalizer maps the raw host of an email address (the part after
+// the '@') to the key that it should be grouped under. The identity
+// normalizer (the default) keeps every host as its own group; a grouping
+// normalizer such as the one returned by NewRegistrableDomainNormalizer
+// collapses hosts down to their registrable domain.
+type DomainNormalizer func(host string) (string, error)
+
+// identityDomainNormalizer is the default DomainNormalizer: it performs no
+// grouping, preserving the historical "one bucket per host" behaviour.
+func identityDomainNormalizer(host string) (string, error) {
+	return host, nil
+}
+
+// DeliverabilityPolicy controls what AddEmailAddressCtx does with an email
+// whose domain has no MX records. SPF only authorizes senders, not
+// recipients, and without a connecting client IP its result reflects a
+// record's catch-all qualifier rather than whether the domain can
+// receive mail, so it is reported on DomainCount but does not drive this
+// policy.
+type DeliverabilityPolicy int
+
+const (
+	// DeliverabilityPolicyKeep counts the email normally regardless of its
+	// deliverability result. This is the default.
+	DeliverabilityPolicyKeep DeliverabilityPolicy = iota
+	// DeliverabilityPolicyDrop discards the email instead of counting it
+	DeliverabilityPolicyDrop
+	// DeliverabilityPolicyDownweight counts only a sample of such emails,
+	// at the rate configured by Options.DownweightSampleRate
+	DeliverabilityPolicyDownweight
+	// DeliverabilityPolicyBucket counts the email as usual but under a
+	// separate "(undeliverable)" bucket for its domain, so deliverable and
+	// undeliverable traffic can be told apart in GetDomainCounts
+	DeliverabilityPolicyBucket
+)
+
+// undeliverableBucketSuffix is appended to a domain's grouping key under
+// DeliverabilityPolicyBucket
+const undeliverableBucketSuffix = " (undeliverable)"
+
+// Filter decides whether an email address should be counted at all.
+// *filter.Ruleset and *filter.Watcher both satisfy this interface; it is
+// declared here rather than importing the filter package's types directly
+// so Options doesn't tie EmailDomainsList to one particular rule format.
+type Filter interface {
+	Allow(email string) bool
+}
+
+// Options configures an EmailDomainsList
+type Options struct {
+	// DomainNormalizer maps a host to the key it should be grouped under.
+	// Defaults to the identity normalizer (no grouping) when left nil.
+	DomainNormalizer DomainNormalizer
+
+	// LocalPartNormalizer maps an email's local part to the form it should
+	// be de-duplicated on, eg. collapsing Gmail's "+tag" and dot variants.
+	// Defaults to DefaultLocalPartNormalizer (no change) when left nil.
+	LocalPartNormalizer LocalPartNormalizer
+
+	// DeliverabilityChecker, if set, is consulted by AddEmailAddressCtx
+	// before an email is counted. Leave nil to skip deliverability checks
+	// entirely (the default).
+	DeliverabilityChecker *deliverability.Checker
+
+	// DeliverabilityPolicy decides what happens to emails whose domain has
+	// no MX records (see the DeliverabilityPolicy doc comment for why SPF
+	// doesn't drive this). Only takes effect when DeliverabilityChecker is
+	// set. Defaults to DeliverabilityPolicyKeep.
+	DeliverabilityPolicy DeliverabilityPolicy
+
+	// DownweightSampleRate is the sampling rate used by
+	// DeliverabilityPolicyDownweight: 1 in DownweightSampleRate emails are
+	// counted, the rest are dropped. Defaults to 10.
+	DownweightSampleRate int
+
+	// Filter, if set, is consulted by AddEmailAddressCtx before an email is
+	// counted. Emails it rejects are tallied against their domain's Skipped
+	// count instead of NumberOfEmailAddresses. Leave nil to count every
+	// email (the default).
+	Filter Filter
+}
+
 // A Data Structure to store the domains with their corresponding
 // email addresses in a sorted order (based on how many email addresses they have)
+//
+// An EmailDomainsList is safe for concurrent use: AddEmailAddressCtx,
+// GetDomainCounts and GetDomainGroups are all guarded by mu, since a job
+// importing rows and a caller polling results can run at the same time
+// (eg. the importer package commits batches while the HTTP server serves
+// GET /jobs/{id}/results).
 type EmailDomainsList struct {
-	domainToElement map[string]*list.Element
-	domainList      *list.List
+	mu sync.Mutex
+
+	domainToElement       map[string]*list.Element
+	domainList            *list.List
+	domainNormalizer      DomainNormalizer
+	localPartNormalizer   LocalPartNormalizer
+	deliverabilityChecker *deliverability.Checker
+	deliverabilityPolicy  DeliverabilityPolicy
+	downweightSampleRate  int
+	filter                Filter
 }
 
 // Creates a New EmailDomainsList
 func NewEmailDomainsList() *EmailDomainsList {
+	return NewEmailDomainsListWithOptions(Options{})
+}
+
+// Creates a New EmailDomainsList configured with Options, eg. to group
+// counts by registrable domain instead of the raw host after '@'
+func NewEmailDomainsListWithOptions(opts Options) *EmailDomainsList {
+	normalizer := opts.DomainNormalizer
+	if normalizer == nil {
+		normalizer = identityDomainNormalizer
+	}
+
+	localPartNormalizer := opts.LocalPartNormalizer
+	if localPartNormalizer == nil {
+		localPartNormalizer = DefaultLocalPartNormalizer
+	}
+
+	downweightSampleRate := opts.DownweightSampleRate
+	if downweightSampleRate <= 0 {
+		downweightSampleRate = 10
+	}
+
 	return &EmailDomainsList{
-		domainToElement: make(map[string]*list.Element),
-		domainList:      list.New(),
+		domainToElement:       make(map[string]*list.Element),
+		domainList:            list.New(),
+		domainNormalizer:      normalizer,
+		localPartNormalizer:   localPartNormalizer,
+		deliverabilityChecker: opts.DeliverabilityChecker,
+		deliverabilityPolicy:  opts.DeliverabilityPolicy,
+		downweightSampleRate:  downweightSampleRate,
+		filter:                opts.Filter,
 	}
 }
 
@@ -98,26 +237,94 @@ func (edl *EmailDomainsList) getListElementForDomain(emailDomain string) *list.E
 	return element
 }
 
-// Add an email address to the data structure and update its values
+// Add an email address to the data structure and update its values. It is
+// equivalent to AddEmailAddressCtx with context.Background(), so no
+// deliverability check will be given the chance to time out or be
+// cancelled; use AddEmailAddressCtx directly if that matters.
 func (edl *EmailDomainsList) AddEmailAddress(email string) error {
-	emailDomain, err := domainOfEmailAddress(email)
+	return edl.AddEmailAddressCtx(context.Background(), email)
+}
+
+// AddEmailAddressCtx is AddEmailAddress, but first consults
+// Options.DeliverabilityChecker (if configured) and applies
+// Options.DeliverabilityPolicy to the result
+func (edl *EmailDomainsList) AddEmailAddressCtx(ctx context.Context, email string) error {
+	parsed, err := ParseEmailWithNormalizer(email, edl.localPartNormalizer)
 	if err != nil {
 		return err
 	}
 
+	edl.mu.Lock()
+	defer edl.mu.Unlock()
+
+	if edl.filter != nil && !edl.filter.Allow(parsed.Normalized) {
+		emailDomain, err := edl.domainNormalizer(parsed.Domain)
+		if err != nil {
+			return err
+		}
+
+		element := edl.getListElementForDomain(emailDomain)
+		dc := element.Value.(*domainListElement).domainCounters[emailDomain]
+		dc.skipped++
+
+		return nil
+	}
+
+	var deliverabilityResult deliverability.Deliverability
+	bucketUndeliverable := false
+	if edl.deliverabilityChecker != nil {
+		deliverabilityResult, err = edl.deliverabilityChecker.Check(ctx, parsed.Domain)
+		if err != nil {
+			return fmt.Errorf("[ERROR][AddEmailAddressCtx]: deliverability check failed for domain %s: %w", parsed.Domain, err)
+		}
+
+		// SPF authorizes senders, not recipients: without a connecting
+		// client IP, evaluateSPF can't test the ip4/ip6/a/mx mechanisms
+		// against anything, so a record falls straight through to its
+		// catch-all qualifier. A domain publishing the common "v=spf1 mx
+		// -all" (valid MX, receives mail fine) would resolve to Fail on
+		// that basis alone. MX presence is the only signal here that
+		// actually reflects whether the domain can receive mail, so only
+		// it drives the policy; deliverabilityResult.SPF is still reported
+		// on DomainCount for visibility, not used to decide undeliverable.
+		if !deliverabilityResult.HasMX {
+			switch edl.deliverabilityPolicy {
+			case DeliverabilityPolicyDrop:
+				return nil
+			case DeliverabilityPolicyDownweight:
+				if fnv32(parsed.Normalized)%uint32(edl.downweightSampleRate) != 0 {
+					return nil
+				}
+			case DeliverabilityPolicyBucket:
+				bucketUndeliverable = true
+			}
+		}
+	}
+
+	emailDomain, err := edl.domainNormalizer(parsed.Domain)
+	if err != nil {
+		return err
+	}
+	if bucketUndeliverable {
+		emailDomain += undeliverableBucketSuffix
+		parsed.Domain += undeliverableBucketSuffix
+	}
+
 	element := edl.getListElementForDomain(emailDomain)
 	elementValue := element.Value.(*domainListElement)
 	dc := elementValue.domainCounters[emailDomain]
-	// Note that this can be a more sophisticated check
-	// eg. test@domain.com and test+tag@domain.com will seem like
-	// different domains although they are the same
-	_, emailAlreadyCounted := dc.emails[email]
+	_, emailAlreadyCounted := dc.emails[parsed.Normalized]
 	if emailAlreadyCounted {
 		fmt.Printf("[WARNING][AddEmailAddress]: email \"%s\" has already been counted. Skipping...\n", email)
 		return nil
 	}
 
-	dc.emails[email] = true
+	dc.emails[parsed.Normalized] = true
+	dc.hostnames[parsed.Domain] = dc.hostnames[parsed.Domain] + 1
+	if !dc.deliverabilitySet {
+		dc.deliverability = deliverabilityResult
+		dc.deliverabilitySet = true
+	}
 	elementValue.domainCounters[emailDomain] = nil // this might be unnecessary
 	delete(elementValue.domainCounters, emailDomain)
 
@@ -146,20 +353,88 @@ func (edl *EmailDomainsList) AddEmailAddress(email string) error {
 type DomainCount struct {
 	Domain                 string
 	NumberOfEmailAddresses int
+	// Deliverability is the zero value unless Options.DeliverabilityChecker
+	// was configured, in which case it holds the result computed for the
+	// first email counted against this domain.
+	Deliverability deliverability.Deliverability
+	// Skipped is the number of emails for this domain that Options.Filter
+	// rejected before they were counted. Always 0 unless Options.Filter was
+	// configured.
+	Skipped int
 }
 
 // Get list of email addresses and the number of emails associated
 // with each address. The list is sorted by the number of email
-// addresses for each domain (descending)
+// addresses for each domain (descending). When the EmailDomainsList was
+// created with a grouping DomainNormalizer, Domain is the normalized
+// (eg. registrable) domain rather than the raw host.
 func (edl *EmailDomainsList) GetDomainCounts() []DomainCount {
+	edl.mu.Lock()
+	defer edl.mu.Unlock()
+
 	rv := make([]DomainCount, 0)
 
 	for e := edl.domainList.Back(); e != nil; e = e.Prev() {
 		value := e.Value.(*domainListElement)
-		for domain, _ := range value.domainCounters {
+		for domain, dc := range value.domainCounters {
 			rv = append(rv, DomainCount{
 				Domain:                 domain,
 				NumberOfEmailAddresses: value.count,
+				Deliverability:         dc.deliverability,
+				Skipped:                dc.skipped,
+			})
+		}
+	}
+
+	return rv
+}
+
+// A child hostname rolled up under a registrable domain, with the number
+// of email addresses seen for that exact hostname
+type HostnameCount struct {
+	Hostname               string
+	NumberOfEmailAddresses int
+}
+
+// A registrable domain together with the child hostnames that were rolled
+// up under it by the DomainNormalizer, eg. "mail.corp.example.co.uk" and
+// "shop.example.co.uk" both rolling up under "example.co.uk"
+type DomainGroup struct {
+	Domain                 string
+	NumberOfEmailAddresses int
+	Hostnames              []HostnameCount
+}
+
+// Get the list of domain groups, each with the child hostnames that were
+// normalized into it. The list is sorted by the number of email addresses
+// per domain (descending); hostnames within a group are sorted the same
+// way. When no grouping DomainNormalizer is in use, each group with at
+// least one counted email has exactly one hostname matching its Domain; a
+// group whose emails were all rejected by Options.Filter has none.
+func (edl *EmailDomainsList) GetDomainGroups() []DomainGroup {
+	edl.mu.Lock()
+	defer edl.mu.Unlock()
+
+	rv := make([]DomainGroup, 0)
+
+	for e := edl.domainList.Back(); e != nil; e = e.Prev() {
+		value := e.Value.(*domainListElement)
+		for domain, dc := range value.domainCounters {
+			hostnames := make([]HostnameCount, 0, len(dc.hostnames))
+			for hostname, count := range dc.hostnames {
+				hostnames = append(hostnames, HostnameCount{
+					Hostname:               hostname,
+					NumberOfEmailAddresses: count,
+				})
+			}
+			sort.Slice(hostnames, func(i, j int) bool {
+				return hostnames[i].NumberOfEmailAddresses > hostnames[j].NumberOfEmailAddresses
+			})
+
+			rv = append(rv, DomainGroup{
+				Domain:                 domain,
+				NumberOfEmailAddresses: value.count,
+				Hostnames:              hostnames,
 			})
 		}
 	}
@@ -171,6 +446,12 @@ func (edl *EmailDomainsList) GetDomainCounts() []DomainCount {
 // and returns a sorted list of the domains with the number of email addresses
 // per domain (see GetDomainCounts). The CSV must contain an 'email' field
 func PorcessCSV(filename string) []DomainCount {
+	return PorcessCSVWithOptions(filename, Options{})
+}
+
+// Same as PorcessCSV but lets the caller configure the EmailDomainsList,
+// eg. to group counts by registrable domain via Options.DomainNormalizer
+func PorcessCSVWithOptions(filename string, opts Options) []DomainCount {
 	f, err := os.Open(filename)
 	if err != nil {
 		fmt.Printf("[Error][ProcessCSV]: Failed to open load file %s. Error: %v", filename, err)
@@ -193,7 +474,7 @@ func PorcessCSV(filename string) []DomainCount {
 		return make([]DomainCount, 0)
 	}
 
-	edl := NewEmailDomainsList()
+	edl := NewEmailDomainsListWithOptions(opts)
 	for row, err := reader.Read(); err != io.EOF; row, err = reader.Read() {
 		entry := make(map[string]string)
 		if len(row) != len(headers) {
@@ -205,7 +486,9 @@ func PorcessCSV(filename string) []DomainCount {
 		}
 
 		email := entry["email"]
-		edl.AddEmailAddress(email)
+		if err := edl.AddEmailAddress(email); err != nil {
+			fmt.Printf("[WARNING][PorcessCSV]: Skipping row %v. Error: %v\n", row, err)
+		}
 	}
 
 	if err != nil {
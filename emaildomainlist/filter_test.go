@@ -0,0 +1,91 @@
+package emaildomainlist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PanagiotisPtr/csv-email-counter/filter"
+)
+
+func TestAddEmailAddressSkipsBlockedEmails(t *testing.T) {
+	rs, err := filter.ParseRuleset(strings.NewReader("*@spam.example\n"), filter.ModeBlocklist)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	edl := NewEmailDomainsListWithOptions(Options{
+		Filter: rs,
+	})
+
+	assertNoError(edl.AddEmailAddress("a@spam.example"), t)
+	assertNoError(edl.AddEmailAddress("b@good.example"), t)
+
+	result := edl.GetDomainCounts()
+	if len(result) != 2 {
+		t.Fatalf("expected 2 domains, got %d", len(result))
+	}
+
+	for _, dc := range result {
+		switch dc.Domain {
+		case "spam.example":
+			if dc.NumberOfEmailAddresses != 0 {
+				t.Errorf("expected spam.example to have 0 counted emails, got %d", dc.NumberOfEmailAddresses)
+			}
+			if dc.Skipped != 1 {
+				t.Errorf("expected spam.example to have 1 skipped email, got %d", dc.Skipped)
+			}
+		case "good.example":
+			if dc.NumberOfEmailAddresses != 1 {
+				t.Errorf("expected good.example to have 1 counted email, got %d", dc.NumberOfEmailAddresses)
+			}
+			if dc.Skipped != 0 {
+				t.Errorf("expected good.example to have 0 skipped emails, got %d", dc.Skipped)
+			}
+		default:
+			t.Errorf("unexpected domain %s", dc.Domain)
+		}
+	}
+}
+
+func TestAddEmailAddressFilterMatchesDisplayNameForms(t *testing.T) {
+	rs, err := filter.ParseRuleset(strings.NewReader("*@spam.example\n"), filter.ModeBlocklist)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	edl := NewEmailDomainsListWithOptions(Options{
+		Filter: rs,
+	})
+
+	assertNoError(edl.AddEmailAddress(`"Bob" <a@spam.example>`), t)
+
+	result := edl.GetDomainCounts()
+	if len(result) != 1 {
+		t.Fatalf("expected 1 domain, got %d", len(result))
+	}
+	if result[0].NumberOfEmailAddresses != 0 {
+		t.Errorf("expected the display-name form to still be blocked, got %d counted emails", result[0].NumberOfEmailAddresses)
+	}
+	if result[0].Skipped != 1 {
+		t.Errorf("expected 1 skipped email, got %d", result[0].Skipped)
+	}
+}
+
+func TestAddEmailAddressAllowlistOnlyCountsMatches(t *testing.T) {
+	rs, err := filter.ParseRuleset(strings.NewReader("*@trusted.example\n"), filter.ModeAllowlist)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	edl := NewEmailDomainsListWithOptions(Options{
+		Filter: rs,
+	})
+
+	assertNoError(edl.AddEmailAddress("a@trusted.example"), t)
+	assertNoError(edl.AddEmailAddress("b@untrusted.example"), t)
+
+	result := edl.GetDomainCounts()
+	if len(result) != 2 {
+		t.Fatalf("expected 2 domains, got %d", len(result))
+	}
+}
@@ -0,0 +1,46 @@
+package emaildomainlist
+
+import (
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// NewRegistrableDomainNormalizer returns a DomainNormalizer that groups
+// hosts by their registrable domain (eTLD+1), as determined by the Public
+// Suffix List. For example "mail.corp.example.co.uk" and
+// "shop.example.co.uk" both normalize to "example.co.uk", while
+// "example.com" is left as-is.
+//
+// If no registrable domain can be derived for a host (eg. the host is
+// itself a public suffix such as "co.uk", or is malformed), the normalizer
+// falls back to returning the host unchanged rather than erroring, so a
+// handful of odd rows never abort an entire import. A TLD the list does
+// not know is treated as a one-label public suffix, so such hosts are
+// grouped by their last two labels unless treatUnknownTLDsAsPrivate is
+// true.
+//
+// When treatUnknownTLDsAsPrivate is true, hosts whose suffix is not part
+// of the ICANN section of the Public Suffix List (eg. dynamic DNS or
+// hosting providers listed in the "private" section) are also left
+// ungrouped, since collapsing them to their registered owner's domain
+// would group unrelated users together.
+func NewRegistrableDomainNormalizer(treatUnknownTLDsAsPrivate bool) DomainNormalizer {
+	return func(host string) (string, error) {
+		lowerHost := strings.ToLower(host)
+
+		if treatUnknownTLDsAsPrivate {
+			_, icann := publicsuffix.PublicSuffix(lowerHost)
+			if !icann {
+				return host, nil
+			}
+		}
+
+		registrableDomain, err := publicsuffix.EffectiveTLDPlusOne(lowerHost)
+		if err != nil {
+			return host, nil
+		}
+
+		return registrableDomain, nil
+	}
+}
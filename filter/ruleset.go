@@ -0,0 +1,126 @@
+// Package filter provides blocklist/allowlist rulesets for email
+// addresses, loaded from a plain-text rule file and hot-reloadable via
+// Watcher.
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Mode decides what a Ruleset match means for AddEmailAddress
+type Mode int
+
+const (
+	// ModeBlocklist counts every email except those matching a rule
+	ModeBlocklist Mode = iota
+	// ModeAllowlist counts only emails matching a rule
+	ModeAllowlist
+)
+
+// Ruleset is a compiled, immutable set of match rules. Build one with
+// ParseRuleset or LoadRuleset; use Watcher to keep one loaded from disk
+// up to date.
+type Ruleset struct {
+	mode  Mode
+	rules []*regexp.Regexp
+}
+
+// ParseRuleset reads rules from r, one per line. Blank lines and lines
+// starting with '#' are ignored. A line starting with "regex:" is
+// compiled as a regular expression (case-insensitive); any other line is
+// a wildcard pattern where '*' matches any run of characters, eg.
+// "*@spam.example", "noreply@*" or "@disposable-tld.xyz" (a bare "@host"
+// is equivalent to "*@host").
+func ParseRuleset(r io.Reader, mode Mode) (*Ruleset, error) {
+	rs := &Ruleset{mode: mode}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		re, err := compileRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", line, err)
+		}
+		rs.rules = append(rs.rules, re)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+// LoadRuleset reads and parses the rule file at path
+func LoadRuleset(path string, mode Mode) (*Ruleset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseRuleset(f, mode)
+}
+
+func compileRule(line string) (*regexp.Regexp, error) {
+	if rest, ok := strings.CutPrefix(line, "regex:"); ok {
+		return regexp.Compile("(?i)^(?:" + strings.TrimSpace(rest) + ")$")
+	}
+	return compileGlob(line)
+}
+
+// compileGlob turns a wildcard pattern into an anchored, case-insensitive
+// regular expression, escaping everything except '*'
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "@") {
+		pattern = "*" + pattern
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(?i)^")
+	for _, part := range strings.Split(pattern, "*") {
+		if part != "" {
+			sb.WriteString(regexp.QuoteMeta(part))
+		}
+		sb.WriteString(".*")
+	}
+	pattern = sb.String()
+	pattern = strings.TrimSuffix(pattern, ".*") + "$"
+
+	return regexp.Compile(pattern)
+}
+
+// Matches reports whether email matches any rule in the set, regardless
+// of Mode
+func (rs *Ruleset) Matches(email string) bool {
+	for _, rule := range rs.rules {
+		if rule.MatchString(email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allow reports whether email should be counted under this ruleset: in
+// ModeBlocklist, emails that don't match any rule are allowed; in
+// ModeAllowlist, only emails that match a rule are allowed.
+func (rs *Ruleset) Allow(email string) bool {
+	matched := rs.Matches(email)
+	if rs.mode == ModeAllowlist {
+		return matched
+	}
+	return !matched
+}
+
+// Len returns the number of rules in the set
+func (rs *Ruleset) Len() int {
+	return len(rs.rules)
+}
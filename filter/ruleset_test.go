@@ -0,0 +1,70 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRulesetBlocklistExactAndWildcard(t *testing.T) {
+	rs, err := ParseRuleset(strings.NewReader(`
+# comment
+*@spam.example
+noreply@*
+@disposable-tld.xyz
+exact@example.com
+`), ModeBlocklist)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rs.Len() != 4 {
+		t.Fatalf("expected 4 rules, got %d", rs.Len())
+	}
+
+	cases := map[string]bool{
+		"a@spam.example":          false,
+		"noreply@anything":        false,
+		"user@disposable-tld.xyz": false,
+		"exact@example.com":       false,
+		"keep@example.com":        true,
+	}
+	for email, wantAllowed := range cases {
+		if allowed := rs.Allow(email); allowed != wantAllowed {
+			t.Errorf("Allow(%q) = %v, want %v", email, allowed, wantAllowed)
+		}
+	}
+}
+
+func TestRulesetAllowlist(t *testing.T) {
+	rs, err := ParseRuleset(strings.NewReader("*@trusted.example\n"), ModeAllowlist)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !rs.Allow("a@trusted.example") {
+		t.Errorf("expected a@trusted.example to be allowed")
+	}
+	if rs.Allow("a@untrusted.example") {
+		t.Errorf("expected a@untrusted.example to be rejected")
+	}
+}
+
+func TestRulesetRegexRule(t *testing.T) {
+	rs, err := ParseRuleset(strings.NewReader(`regex:^bounce-[0-9]+@example\.com$`), ModeBlocklist)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rs.Allow("bounce-123@example.com") {
+		t.Errorf("expected bounce-123@example.com to be blocked")
+	}
+	if !rs.Allow("bounce-abc@example.com") {
+		t.Errorf("expected bounce-abc@example.com to be allowed")
+	}
+}
+
+func TestParseRulesetInvalidRegex(t *testing.T) {
+	_, err := ParseRuleset(strings.NewReader("regex:(unclosed"), ModeBlocklist)
+	if err == nil {
+		t.Errorf("expected an error for an invalid regex rule")
+	}
+}
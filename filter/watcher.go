@@ -0,0 +1,118 @@
+package filter
+
+import (
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher keeps a Ruleset loaded from a file, swapping in a freshly
+// compiled Ruleset whenever the file changes on disk, without ever
+// exposing a partially-updated or nil ruleset to readers.
+type Watcher struct {
+	path    string
+	mode    Mode
+	current atomic.Pointer[Ruleset]
+	fw      *fsnotify.Watcher
+	done    chan struct{}
+
+	// onReload, if set, is called after every reload attempt with the
+	// resulting rule count (and a nil error), or the error that kept the
+	// previously loaded Ruleset in place.
+	onReload func(ruleCount int, err error)
+}
+
+// WatchFile loads the rule file at path and watches it for changes. The
+// containing directory, not the file itself, is watched so edits that
+// replace the file (eg. `mv` after an atomic rewrite) are still picked up.
+func WatchFile(path string, mode Mode, onReload func(ruleCount int, err error)) (*Watcher, error) {
+	ruleset, err := LoadRuleset(path, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := fw.Add(filepath.Dir(path)); err != nil {
+		fw.Close()
+		return nil, err
+	}
+
+	w := &Watcher{
+		path:     path,
+		mode:     mode,
+		fw:       fw,
+		done:     make(chan struct{}),
+		onReload: onReload,
+	}
+	w.current.Store(ruleset)
+
+	go w.run()
+
+	return w, nil
+}
+
+func (w *Watcher) run() {
+	for {
+		select {
+		case event, ok := <-w.fw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(w.path) {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+
+			ruleset, err := LoadRuleset(w.path, w.mode)
+			if err != nil {
+				if w.onReload != nil {
+					w.onReload(0, err)
+				}
+				continue
+			}
+			w.current.Store(ruleset)
+			if w.onReload != nil {
+				w.onReload(ruleset.Len(), nil)
+			}
+
+		case err, ok := <-w.fw.Errors:
+			if !ok {
+				return
+			}
+			if w.onReload != nil {
+				w.onReload(0, err)
+			}
+
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Ruleset returns the currently loaded Ruleset
+func (w *Watcher) Ruleset() *Ruleset {
+	return w.current.Load()
+}
+
+// Allow is a convenience for Ruleset().Allow(email) against whatever
+// Ruleset is currently loaded
+func (w *Watcher) Allow(email string) bool {
+	return w.current.Load().Allow(email)
+}
+
+// RuleCount returns the number of rules in the currently loaded Ruleset
+func (w *Watcher) RuleCount() int {
+	return w.current.Load().Len()
+}
+
+// Close stops watching the rule file
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.fw.Close()
+}
@@ -0,0 +1,51 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.txt")
+	if err := os.WriteFile(path, []byte("*@spam.example\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reloaded := make(chan int, 4)
+	w, err := WatchFile(path, ModeBlocklist, func(ruleCount int, err error) {
+		if err == nil {
+			reloaded <- ruleCount
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	if w.RuleCount() != 1 {
+		t.Fatalf("expected 1 rule, got %d", w.RuleCount())
+	}
+	if w.Allow("a@spam.example") {
+		t.Errorf("expected a@spam.example to be blocked")
+	}
+
+	if err := os.WriteFile(path, []byte("*@spam.example\n*@also-blocked.example\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case count := <-reloaded:
+		if count != 2 {
+			t.Errorf("expected 2 rules after reload, got %d", count)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for the watcher to reload")
+	}
+
+	if w.Allow("a@also-blocked.example") {
+		t.Errorf("expected a@also-blocked.example to be blocked after reload")
+	}
+}
@@ -0,0 +1,259 @@
+// Package importer runs large CSV (or zipped CSVs) imports as background
+// jobs: parsing is decoupled from counting via a worker pool and results
+// are committed to an emaildomainlist.EmailDomainsList in batches, so
+// memory stays bounded and progress can be polled while huge files are
+// still being processed.
+package importer
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/PanagiotisPtr/csv-email-counter/emaildomainlist"
+)
+
+// defaultBatchSize is how many parsed rows are committed to the
+// EmailDomainsList at a time
+const defaultBatchSize = 10_000
+
+// ImporterOptions configures an Importer
+type ImporterOptions struct {
+	// BatchSize is how many parsed rows are committed to the
+	// EmailDomainsList per batch. Defaults to 10,000.
+	BatchSize int
+	// Workers is the size of the worker pool that parses and validates
+	// rows concurrently. Defaults to runtime.NumCPU().
+	Workers int
+	// EmailDomainsListOptions is passed through to
+	// emaildomainlist.NewEmailDomainsListWithOptions for every job.
+	EmailDomainsListOptions emaildomainlist.Options
+}
+
+// Importer runs CSV imports as background jobs identified by a job ID
+type Importer struct {
+	batchSize  int
+	workers    int
+	edlOptions emaildomainlist.Options
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// NewImporter creates an Importer configured with opts
+func NewImporter(opts ImporterOptions) *Importer {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return &Importer{
+		batchSize:  batchSize,
+		workers:    workers,
+		edlOptions: opts.EmailDomainsListOptions,
+		jobs:       make(map[string]*job),
+	}
+}
+
+// Submit starts importing source in the background and returns a job ID
+// that Status, Stop and Results can use to refer to it
+func (im *Importer) Submit(source Source) (string, error) {
+	jobID, err := newJobID()
+	if err != nil {
+		return "", fmt.Errorf("[ERROR][Submit]: failed to generate a job ID: %w", err)
+	}
+
+	j := newJob(emaildomainlist.NewEmailDomainsListWithOptions(im.edlOptions))
+
+	im.mu.Lock()
+	im.jobs[jobID] = j
+	im.mu.Unlock()
+
+	go im.run(j, source)
+
+	return jobID, nil
+}
+
+// Status reports the current state of jobID. A jobID that was never
+// submitted reports StateNone.
+func (im *Importer) Status(jobID string) Status {
+	j, ok := im.job(jobID)
+	if !ok {
+		return Status{State: StateNone}
+	}
+	return j.snapshot()
+}
+
+// Stop asks jobID to stop as soon as its in-flight batch is committed.
+// Rows already committed are kept; rows still in the pipeline are
+// discarded.
+func (im *Importer) Stop(jobID string) error {
+	j, ok := im.job(jobID)
+	if !ok {
+		return fmt.Errorf("[ERROR][Stop]: no such job %s", jobID)
+	}
+	j.requestStop()
+	return nil
+}
+
+// Results returns the sorted domain counts committed so far for jobID.
+// It can be called while the job is still importing to observe progress.
+func (im *Importer) Results(jobID string) ([]emaildomainlist.DomainCount, error) {
+	j, ok := im.job(jobID)
+	if !ok {
+		return nil, fmt.Errorf("[ERROR][Results]: no such job %s", jobID)
+	}
+	return j.edl.GetDomainCounts(), nil
+}
+
+func (im *Importer) job(jobID string) (*job, bool) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	j, ok := im.jobs[jobID]
+	return j, ok
+}
+
+// run drives a single job's pipeline: one goroutine reads CSV rows from
+// source into rowsCh, a pool of Workers goroutines parses/validates each
+// row into resultsCh, and this goroutine commits valid rows to the job's
+// EmailDomainsList in BatchSize batches.
+func (im *Importer) run(j *job, source Source) {
+	j.start()
+
+	streams, err := source.Open()
+	if err != nil {
+		j.finish(StateFailed, err)
+		return
+	}
+
+	rowsCh := make(chan rowItem, im.batchSize)
+	go func() {
+		defer close(rowsCh)
+		for _, stream := range streams {
+			readCSVStream(stream, rowsCh)
+			if stream.Close != nil {
+				stream.Close()
+			}
+		}
+	}()
+
+	resultsCh := make(chan rowItem, im.batchSize)
+	var workers sync.WaitGroup
+	for w := 0; w < im.workers; w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for r := range rowsCh {
+				resultsCh <- parseRow(r)
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(resultsCh)
+	}()
+
+	batch := make([]string, 0, im.batchSize)
+	commit := func() {
+		for _, email := range batch {
+			if err := j.edl.AddEmailAddress(email); err != nil {
+				j.recordSkip(err.Error())
+				continue
+			}
+			j.recordProcessed()
+		}
+		batch = batch[:0]
+	}
+
+	stopping := false
+	for r := range resultsCh {
+		if !stopping && j.stopRequested() {
+			stopping = true
+			j.setState(StateStopping)
+		}
+		if stopping {
+			continue // drain the channel without doing further work
+		}
+
+		if r.err != nil {
+			j.recordSkip(r.err.Error())
+			continue
+		}
+
+		batch = append(batch, r.email)
+		if len(batch) >= im.batchSize {
+			commit()
+		}
+	}
+
+	commit()
+	j.finish(StateFinished, nil)
+}
+
+// parseRow validates r's email, turning parse failures into a skip reason
+func parseRow(r rowItem) rowItem {
+	if r.err != nil {
+		return r
+	}
+	if _, err := emaildomainlist.ParseEmail(r.email); err != nil {
+		return rowItem{err: err}
+	}
+	return r
+}
+
+// readCSVStream reads stream as a CSV with an 'email' column, sending one
+// rowItem per data row (or a single error row if the stream itself can't
+// be read as a CSV with the expected header)
+func readCSVStream(stream CSVStream, rowsCh chan<- rowItem) {
+	reader := csv.NewReader(stream.Reader)
+	headers, err := reader.Read()
+	if err != nil {
+		rowsCh <- rowItem{err: fmt.Errorf("%s: failed to read CSV header: %w", stream.Name, err)}
+		return
+	}
+
+	emailColumn := -1
+	for i, header := range headers {
+		if header == "email" {
+			emailColumn = i
+		}
+	}
+	if emailColumn == -1 {
+		rowsCh <- rowItem{err: fmt.Errorf("%s: missing 'email' header in CSV", stream.Name)}
+		return
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			rowsCh <- rowItem{err: fmt.Errorf("%s: %w", stream.Name, err)}
+			continue
+		}
+		if len(row) != len(headers) {
+			rowsCh <- rowItem{err: fmt.Errorf("%s: row has missing values: %v", stream.Name, row)}
+			continue
+		}
+		rowsCh <- rowItem{email: row[emailColumn]}
+	}
+}
+
+// newJobID returns a random, URL-safe job identifier
+func newJobID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,232 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PanagiotisPtr/csv-email-counter/emaildomainlist"
+)
+
+func waitForState(t *testing.T, im *Importer, jobID string, want State) Status {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	var status Status
+	for time.Now().Before(deadline) {
+		status = im.Status(jobID)
+		if status.State == want {
+			return status
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for job %s to reach state %s, last status: %+v", jobID, want, status)
+	return status
+}
+
+func TestImporterReaderSource(t *testing.T) {
+	csv := "email\ntest1@domain1.com\ntest2@domain1.com\ntest1@domain2.com\n"
+
+	imp := NewImporter(ImporterOptions{})
+	jobID, err := imp.Submit(ReaderSource("emails.csv", strings.NewReader(csv)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	status := waitForState(t, imp, jobID, StateFinished)
+	if status.RowsProcessed != 3 {
+		t.Errorf("expected 3 rows processed, got %d", status.RowsProcessed)
+	}
+
+	results, err := imp.Results(jobID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 domains, got %d", len(results))
+	}
+}
+
+func TestImporterSkipsInvalidRows(t *testing.T) {
+	csv := "email\ntest1@domain1.com\nnot-an-email\ntest2@domain1.com,extra\n"
+
+	imp := NewImporter(ImporterOptions{})
+	jobID, err := imp.Submit(ReaderSource("emails.csv", strings.NewReader(csv)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	status := waitForState(t, imp, jobID, StateFinished)
+	if status.RowsProcessed != 1 {
+		t.Errorf("expected 1 row processed, got %d", status.RowsProcessed)
+	}
+	if status.RowsSkipped != 2 {
+		t.Errorf("expected 2 rows skipped, got %d", status.RowsSkipped)
+	}
+	if len(status.SkipReasons) == 0 {
+		t.Errorf("expected skip reasons to be populated")
+	}
+}
+
+func TestImporterMissingEmailHeaderFinishesWithNoRows(t *testing.T) {
+	csv := "name\nalice\n"
+
+	imp := NewImporter(ImporterOptions{})
+	jobID, err := imp.Submit(ReaderSource("emails.csv", strings.NewReader(csv)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	status := waitForState(t, imp, jobID, StateFinished)
+	if status.RowsProcessed != 0 {
+		t.Errorf("expected 0 rows processed, got %d", status.RowsProcessed)
+	}
+	if status.RowsSkipped != 1 {
+		t.Errorf("expected the missing header to count as 1 skipped row, got %d", status.RowsSkipped)
+	}
+}
+
+func TestImporterStatusOfUnknownJob(t *testing.T) {
+	imp := NewImporter(ImporterOptions{})
+	status := imp.Status("does-not-exist")
+	if status.State != StateNone {
+		t.Errorf("expected %s, got %s", StateNone, status.State)
+	}
+}
+
+func TestImporterBatching(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("email\n")
+	for i := 0; i < 25; i++ {
+		buf.WriteString("address")
+		buf.WriteString(strings.Repeat("x", 1))
+		buf.WriteString("@domain.com\n")
+	}
+
+	imp := NewImporter(ImporterOptions{BatchSize: 10})
+	jobID, err := imp.Submit(ReaderSource("emails.csv", &buf))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	status := waitForState(t, imp, jobID, StateFinished)
+	if status.RowsProcessed != 25 {
+		t.Errorf("expected all 25 rows to be processed across 3 batches, got %d", status.RowsProcessed)
+	}
+
+	results, err := imp.Results(jobID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].NumberOfEmailAddresses != 1 {
+		t.Errorf("expected the duplicate address to collapse into 1 counted domain, got %+v", results)
+	}
+}
+
+func TestImporterZipSource(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := dir + "/emails.zip"
+
+	zipFile, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	zw := zip.NewWriter(zipFile)
+
+	entries := map[string]string{
+		"a.csv": "email\ntest1@domain1.com\n",
+		"b.csv": "email\ntest1@domain2.com\ntest2@domain2.com\n",
+	}
+	for name, contents := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := w.Write([]byte(contents)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	zipFile.Close()
+
+	readZip, err := os.Open(zipPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer readZip.Close()
+
+	imp := NewImporter(ImporterOptions{})
+	jobID, err := imp.Submit(FileSource(readZip))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	status := waitForState(t, imp, jobID, StateFinished)
+	if status.RowsProcessed != 3 {
+		t.Errorf("expected 3 rows processed across both CSVs in the zip, got %d", status.RowsProcessed)
+	}
+
+	results, err := imp.Results(jobID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 domains, got %d", len(results))
+	}
+}
+
+func TestImporterResultsDuringImport(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("email\n")
+	for i := 0; i < 5_000; i++ {
+		buf.WriteString("address")
+		buf.WriteString(strings.Repeat("x", i%50+1))
+		buf.WriteString("@domain.com\n")
+	}
+
+	imp := NewImporter(ImporterOptions{BatchSize: 50})
+	jobID, err := imp.Submit(ReaderSource("emails.csv", &buf))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Poll Results concurrently with run's in-flight batch commits; under
+	// -race this would flag the concurrent map read/write if
+	// EmailDomainsList weren't guarded by its own mutex.
+	for imp.Status(jobID).State == StateImporting || imp.Status(jobID).State == StateNone {
+		if _, err := imp.Results(jobID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	waitForState(t, imp, jobID, StateFinished)
+}
+
+func TestImporterEmailDomainsListOptions(t *testing.T) {
+	imp := NewImporter(ImporterOptions{
+		EmailDomainsListOptions: emaildomainlist.Options{
+			LocalPartNormalizer: emaildomainlist.GmailLocalPartNormalizer,
+		},
+	})
+
+	jobID, err := imp.Submit(ReaderSource("emails.csv", strings.NewReader(
+		"email\ntest.user+promo@gmail.com\ntestuser@gmail.com\n",
+	)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitForState(t, imp, jobID, StateFinished)
+
+	results, err := imp.Results(jobID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].NumberOfEmailAddresses != 1 {
+		t.Errorf("expected the two Gmail variants to collapse into 1 address, got %+v", results)
+	}
+}
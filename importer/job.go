@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PanagiotisPtr/csv-email-counter/emaildomainlist"
+)
+
+// State is the lifecycle of an import job
+type State string
+
+const (
+	StateNone      State = "none"
+	StateImporting State = "importing"
+	StateStopping  State = "stopping"
+	StateFinished  State = "finished"
+	StateFailed    State = "failed"
+)
+
+// Status is a point-in-time report on an import job
+type Status struct {
+	State         State
+	RowsProcessed int
+	RowsSkipped   int
+	// SkipReasons counts skipped rows by the error message that caused
+	// them to be skipped
+	SkipReasons map[string]int
+	Elapsed     time.Duration
+	Err         string
+}
+
+// rowItem is a single row flowing through the parse/count pipeline: an
+// email address to count, or the error that caused it to be skipped
+type rowItem struct {
+	email string
+	err   error
+}
+
+// job tracks the state of a single submitted import
+type job struct {
+	edl *emaildomainlist.EmailDomainsList
+
+	mu            sync.Mutex
+	state         State
+	rowsProcessed int
+	rowsSkipped   int
+	skipReasons   map[string]int
+	startedAt     time.Time
+	elapsed       time.Duration
+	err           error
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+}
+
+func newJob(edl *emaildomainlist.EmailDomainsList) *job {
+	return &job{
+		edl:         edl,
+		state:       StateNone,
+		skipReasons: make(map[string]int),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+func (j *job) requestStop() {
+	j.stopOnce.Do(func() { close(j.stopCh) })
+}
+
+func (j *job) stopRequested() bool {
+	select {
+	case <-j.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (j *job) start() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state = StateImporting
+	j.startedAt = time.Now()
+}
+
+func (j *job) setState(state State) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state = state
+}
+
+func (j *job) recordProcessed() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.rowsProcessed++
+}
+
+func (j *job) recordSkip(reason string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.rowsSkipped++
+	j.skipReasons[reason]++
+}
+
+func (j *job) finish(state State, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state = state
+	j.err = err
+	j.elapsed = time.Since(j.startedAt)
+}
+
+func (j *job) snapshot() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	elapsed := j.elapsed
+	if j.state == StateImporting || j.state == StateStopping {
+		elapsed = time.Since(j.startedAt)
+	}
+
+	skipReasons := make(map[string]int, len(j.skipReasons))
+	for reason, count := range j.skipReasons {
+		skipReasons[reason] = count
+	}
+
+	errMsg := ""
+	if j.err != nil {
+		errMsg = j.err.Error()
+	}
+
+	return Status{
+		State:         j.state,
+		RowsProcessed: j.rowsProcessed,
+		RowsSkipped:   j.rowsSkipped,
+		SkipReasons:   skipReasons,
+		Elapsed:       elapsed,
+		Err:           errMsg,
+	}
+}
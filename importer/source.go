@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CSVStream is a single CSV file to import, with an optional Close to
+// release resources (eg. a zip entry or the underlying file) once the
+// stream has been fully read.
+type CSVStream struct {
+	Name   string
+	Reader io.Reader
+	Close  func() error
+}
+
+// Source provides one or more CSV streams for an Importer to consume. See
+// FileSource, ReaderSource and ZipSource for the supported kinds of input.
+type Source interface {
+	Open() ([]CSVStream, error)
+}
+
+// fileSource wraps an *os.File, importing it as a .zip archive of CSVs
+// when its name ends in ".zip", or as a single CSV otherwise.
+type fileSource struct {
+	file *os.File
+}
+
+// FileSource creates a Source from an already-open *os.File. A file named
+// "*.zip" is walked as a zip archive of one or more CSVs (see ZipSource);
+// any other file is imported as a single CSV.
+func FileSource(file *os.File) Source {
+	return &fileSource{file: file}
+}
+
+func (s *fileSource) Open() ([]CSVStream, error) {
+	if strings.EqualFold(filepath.Ext(s.file.Name()), ".zip") {
+		return ZipSource(s.file).Open()
+	}
+	return []CSVStream{{Name: s.file.Name(), Reader: s.file}}, nil
+}
+
+// readerSource imports a single CSV from an arbitrary io.Reader
+type readerSource struct {
+	name   string
+	reader io.Reader
+}
+
+// ReaderSource creates a Source that imports a single CSV read from r.
+// Since an io.Reader isn't seekable, it can't be walked as a zip archive;
+// use FileSource or ZipSource for those.
+func ReaderSource(name string, r io.Reader) Source {
+	return &readerSource{name: name, reader: r}
+}
+
+func (s *readerSource) Open() ([]CSVStream, error) {
+	return []CSVStream{{Name: s.name, Reader: s.reader}}, nil
+}
+
+// zipSource walks a .zip archive and imports every ".csv" entry in it
+type zipSource struct {
+	file *os.File
+}
+
+// ZipSource creates a Source from an *os.File containing a .zip archive
+// of one or more CSVs
+func ZipSource(file *os.File) Source {
+	return &zipSource{file: file}
+}
+
+func (s *zipSource) Open() ([]CSVStream, error) {
+	info, err := s.file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat zip file %s: %w", s.file.Name(), err)
+	}
+
+	zr, err := zip.NewReader(s.file, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s as a zip archive: %w", s.file.Name(), err)
+	}
+
+	streams := make([]CSVStream, 0, len(zr.File))
+	for _, entry := range zr.File {
+		if entry.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(entry.Name), ".csv") {
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s in zip archive: %w", entry.Name, err)
+		}
+
+		streams = append(streams, CSVStream{
+			Name:   entry.Name,
+			Reader: rc,
+			Close:  rc.Close,
+		})
+	}
+
+	return streams, nil
+}
@@ -1,49 +1,170 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/PanagiotisPtr/csv-email-counter/emaildomainlist"
+	"github.com/PanagiotisPtr/csv-email-counter/filter"
+	"github.com/PanagiotisPtr/csv-email-counter/importer"
 )
 
+// filterWatcher is the blocklist loaded from FILTER_RULES_PATH, or nil if
+// that environment variable isn't set.
+var filterWatcher *filter.Watcher = newFilterWatcher()
+
+var imp = importer.NewImporter(importer.ImporterOptions{
+	EmailDomainsListOptions: emaildomainlist.Options{
+		Filter: filterWatcherOption(),
+	},
+})
+
+// newFilterWatcher loads and watches the blocklist rule file named by the
+// FILTER_RULES_PATH environment variable, if set, logging every reload.
+// Returns nil (no filtering) when the variable is unset.
+func newFilterWatcher() *filter.Watcher {
+	path := os.Getenv("FILTER_RULES_PATH")
+	if path == "" {
+		return nil
+	}
+
+	w, err := filter.WatchFile(path, filter.ModeBlocklist, func(ruleCount int, err error) {
+		if err != nil {
+			log.Printf("[ERROR][FILTER_RULES_PATH]: failed to reload %s: %v", path, err)
+			return
+		}
+		log.Printf("[INFO][FILTER_RULES_PATH]: loaded %d rules from %s", ruleCount, path)
+	})
+	if err != nil {
+		log.Fatalf("[ERROR][FILTER_RULES_PATH]: failed to load %s: %v", path, err)
+	}
+
+	return w
+}
+
+// filterWatcherOption adapts filterWatcher to emaildomainlist.Filter,
+// keeping its nil-ness instead of wrapping it in a non-nil interface value.
+func filterWatcherOption() emaildomainlist.Filter {
+	if filterWatcher == nil {
+		return nil
+	}
+	return filterWatcher
+}
+
 func main() {
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		http.ServeFile(w, r, "static/index.html")
 	})
-	http.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
-		// 2MB file size limit
-		r.ParseMultipartForm(1 << 21)
-		file, _, err := r.FormFile("csv")
-		if err != nil {
-			fmt.Printf("Failed to get handler for file. Error: %v", err)
-			return
-		}
-		defer file.Close()
+	http.HandleFunc("/upload", handleUpload)
+	http.HandleFunc("/jobs/", handleJob)
+	http.HandleFunc("/healthz", handleHealthz)
+	http.ListenAndServe(":80", nil)
+}
 
-		// create temp file
-		tempFile, err := ioutil.TempFile("tmp", "file-*.csv")
-		if err != nil {
-			fmt.Printf("Failed to create temporary file. Error: %v", err)
+// handleHealthz reports liveness along with the number of rules currently
+// loaded from FILTER_RULES_PATH (0 if it isn't set)
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	ruleCount := 0
+	if filterWatcher != nil {
+		ruleCount = filterWatcher.RuleCount()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Status    string `json:"status"`
+		RuleCount int    `json:"ruleCount"`
+	}{Status: "ok", RuleCount: ruleCount})
+}
+
+// handleUpload persists the uploaded CSV (or zip of CSVs) to a temp file
+// and submits it to the Importer, returning the job ID to poll for
+// progress and results.
+func handleUpload(w http.ResponseWriter, r *http.Request) {
+	// 2MB file size limit
+	r.ParseMultipartForm(1 << 21)
+	file, header, err := r.FormFile("csv")
+	if err != nil {
+		fmt.Printf("Failed to get handler for file. Error: %v", err)
+		http.Error(w, "failed to read uploaded file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	// create temp file, preserving the extension so the Importer can tell
+	// a .zip upload from a plain .csv one
+	tempFile, err := ioutil.TempFile("tmp", "file-*"+filepath.Ext(header.Filename))
+	if err != nil {
+		fmt.Printf("Failed to create temporary file. Error: %v", err)
+		http.Error(w, "failed to process uploaded file", http.StatusInternalServerError)
+		return
+	}
+	defer tempFile.Close()
+
+	if _, err := io.Copy(tempFile, file); err != nil {
+		fmt.Printf("Failed to write temporary file. Error: %v", err)
+		http.Error(w, "failed to process uploaded file", http.StatusInternalServerError)
+		return
+	}
+
+	// reopen for reading: the Importer streams the file itself, and after
+	// the copy tempFile's offset is at its end, where a plain CSV upload
+	// would be read from
+	importFile, err := os.Open(tempFile.Name())
+	if err != nil {
+		fmt.Printf("Failed to reopen temporary file. Error: %v", err)
+		http.Error(w, "failed to process uploaded file", http.StatusInternalServerError)
+		return
+	}
+
+	jobID, err := imp.Submit(importer.FileSource(importFile))
+	if err != nil {
+		fmt.Printf("Failed to submit import job. Error: %v", err)
+		http.Error(w, "failed to start import", http.StatusInternalServerError)
+		return
+	}
+	go cleanupWhenDone(jobID, importFile, tempFile.Name())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		JobID string `json:"jobId"`
+	}{JobID: jobID})
+}
+
+// cleanupWhenDone removes the temporary upload file once jobID has
+// finished (or failed) importing it
+func cleanupWhenDone(jobID string, file *os.File, path string) {
+	for {
+		switch imp.Status(jobID).State {
+		case importer.StateFinished, importer.StateFailed:
+			file.Close()
+			os.Remove(path)
 			return
+		default:
+			time.Sleep(time.Second)
 		}
-		defer tempFile.Close()
+	}
+}
 
-		data, err := ioutil.ReadAll(file)
+// handleJob serves GET /jobs/{id} for status polling and
+// GET /jobs/{id}/results for the sorted domain counts
+func handleJob(w http.ResponseWriter, r *http.Request) {
+	jobID, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+
+	if action == "results" {
+		results, err := imp.Results(jobID)
 		if err != nil {
-			fmt.Printf("Failed to read file contents. Error: %v", err)
+			http.NotFound(w, r)
 			return
 		}
-		tempFile.Write(data)
-
-		start := time.Now()
-		results := emaildomainlist.PorcessCSV(tempFile.Name())
-		processingTime := time.Since(start).Milliseconds()
-		os.RemoveAll("/tmp/")
 
 		t, err := template.ParseFiles("static/results.html")
 		if err != nil {
@@ -51,12 +172,19 @@ func main() {
 			return
 		}
 		t.Execute(w, struct {
-			Results        []emaildomainlist.DomainCount
-			ProcessingTime int64
+			Results []emaildomainlist.DomainCount
 		}{
-			Results:        results,
-			ProcessingTime: processingTime,
+			Results: results,
 		})
-	})
-	http.ListenAndServe(":80", nil)
+		return
+	}
+
+	status := imp.Status(jobID)
+	if status.State == importer.StateNone {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
 }